@@ -3,24 +3,34 @@
 // operating systems will transparently convert a shared lock to an exclusive
 // lock. If you Unlock() the gflock from a location where you believe that you
 // have the shared lock, you may accidentally drop the exclusive lock.
+//
+// WARNING: on the common POSIX platforms, the whole-file methods (Lock,
+// RLock, TryLock, TryRLock, Upgrade, Downgrade, Unlock) and the
+// byte-range methods (LockRange, RLockRange, TryLockRange,
+// TryRLockRange, UnlockRange) are backed by different kernel locking
+// APIs and do not exclude one another. Never mix the two families on
+// the same path within a single application; see the package doc of
+// internal/filelock for the underlying reason.
 package gflock
 
 import (
 	"context"
 	"os"
-	"runtime"
 	"sync"
 	"time"
+
+	"github.com/tekintian/gflock/internal/filelock"
 )
 
 // GFlock is the struct type to handle file locking. All fields are unexported,
 // with access to some of the fields provided by getter methods (Path() and Locked()).
 type GFlock struct {
-	path string
-	m    sync.RWMutex
-	fh   *os.File
-	l    bool
-	r    bool
+	path   string
+	m      sync.RWMutex
+	fh     *os.File
+	l      bool
+	r      bool
+	ranges []heldRange
 }
 
 // New returns a new instance of *GFlock. The only parameter
@@ -105,13 +115,24 @@ func tryCtx(ctx context.Context, fn func() (bool, error), retryDelay time.Durati
 	}
 }
 
-func (f *GFlock) setFh() error {
-	// open a new os.File instance
-	// create it if it doesn't exist, and open the file read-only.
-	flags := os.O_CREATE
-	if runtime.GOOS == "aix" {
-		// AIX cannot preform write-lock (ie exclusive) on a
-		// read-only file.
+// setFh opens the backing file, creating it if it doesn't exist.
+// filelock.OpenFlags adds whatever access mode the active backend needs
+// regardless of forceWrite (e.g. the Solaris/AIX fcntl backend, where
+// Lock and RLock share one descriptor that must also be able to Upgrade
+// from one to the other, so it's always opened O_RDWR). forceWrite exists
+// for callers with their own, backend-independent reason to need a
+// write-capable descriptor even when OpenFlags doesn't ask for one, such
+// as byte-range locking, which on POSIX always goes through fcntl(F_SETLK) even on
+// platforms where the whole-file Lock/RLock use flock(2) instead (see
+// lockRange). Whole-file Lock/RLock/TryLock/TryRLock have no such need:
+// on flock(2) platforms a lock is a property of the open file
+// description, not of the access mode it was opened with, so passing
+// forceWrite=false there still lets an exclusive lock be taken, and
+// callers that only ever take shared locks can lock a read-only file or
+// filesystem.
+func (f *GFlock) setFh(forceWrite bool) error {
+	flags := os.O_CREATE | filelock.OpenFlags
+	if forceWrite {
 		flags |= os.O_RDWR
 	} else {
 		flags |= os.O_RDONLY
@@ -128,7 +149,7 @@ func (f *GFlock) setFh() error {
 
 // ensure the file handle is closed if no lock is held
 func (f *GFlock) ensureFhState() {
-	if !f.l && !f.r && f.fh != nil {
+	if !f.l && !f.r && len(f.ranges) == 0 && f.fh != nil {
 		f.fh.Close()
 		f.fh = nil
 	}
@@ -0,0 +1,179 @@
+package gflock
+
+import "github.com/tekintian/gflock/internal/filelock"
+
+// Lock is a blocking call to try and take an exclusive file lock. It will
+// wait until it is able to obtain the exclusive file lock. It's recommended
+// that TryLock() be used over this function. This function may block the
+// ability to query the current Locked() or RLocked() status due to the
+// lock handle mutex being held.
+//
+// If we are already locked, this function short-circuits and returns
+// immediately assuming it can take the mutex lock.
+func (f *GFlock) Lock() error {
+	return f.lock(&f.l, false, filelock.Lock)
+}
+
+// RLock is a blocking call to try and take a shared file lock. It will
+// wait until it is able to obtain the shared file lock. It's recommended
+// that TryRLock() be used over this function. This function may block the
+// ability to query the current Locked() or RLocked() status due to the
+// lock handle mutex being held.
+//
+// If we are already rlocked, this function short-circuits and returns
+// immediately assuming it can take the mutex lock.
+func (f *GFlock) RLock() error {
+	return f.lock(&f.r, false, filelock.RLock)
+}
+
+// lockWrite is Lock, but forces the underlying descriptor to be opened
+// write-capable even on backends that wouldn't otherwise need it for an
+// exclusive lock (see setFh). Used wherever a whole-file exclusive lock
+// may need to share its fh with a later write or range call that does
+// need one: lockedfile.go's Write, which writes back through this same
+// descriptor instead of locking then opening a second one, and
+// LockRange's offset==0, length==0 case, which must leave the fh usable
+// for a later real range call on the same GFlock.
+func (f *GFlock) lockWrite() error {
+	return f.lock(&f.l, true, filelock.Lock)
+}
+
+// rlockWrite is RLock, but forces the underlying descriptor to be opened
+// write-capable, for the same reasons lockWrite does for Lock: used by
+// lockedfile.go's Transform, which may Upgrade the shared lock it starts
+// with to exclusive and then write back through the same descriptor,
+// and by RLockRange's offset==0, length==0 case.
+func (f *GFlock) rlockWrite() error {
+	return f.lock(&f.r, true, filelock.RLock)
+}
+
+func (f *GFlock) lock(locked *bool, forceWrite bool, do func(filelock.File) error) error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if *locked {
+		return nil
+	}
+
+	if f.fh == nil {
+		if err := f.setFh(forceWrite); err != nil {
+			return err
+		}
+		defer f.ensureFhState()
+	}
+
+	if err := do(f.fh); err != nil {
+		return err
+	}
+
+	*locked = true
+	return nil
+}
+
+// Unlock is a function to unlock the file. This file takes a RW-mutex lock,
+// so while it is running the Locked() and RLocked() functions will be
+// blocked.
+//
+// This function short-circuits if we are unlocked already. If not, it
+// releases the whole-file lock taken by Lock/RLock (via
+// filelock.Unlock()) as well as any byte ranges taken by LockRange or
+// RLockRange, releasing exactly what this GFlock holds.
+func (f *GFlock) Unlock() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if (!f.l && !f.r && len(f.ranges) == 0) || f.fh == nil {
+		return nil
+	}
+
+	if f.l || f.r {
+		if err := filelock.Unlock(f.fh); err != nil {
+			return err
+		}
+	}
+
+	for _, hr := range f.ranges {
+		if err := filelock.UnlockRange(f.fh, hr.start, rangeLength(hr.start, hr.end)); err != nil {
+			return err
+		}
+	}
+
+	f.fh.Close()
+
+	f.l = false
+	f.r = false
+	f.ranges = nil
+	f.fh = nil
+
+	return nil
+}
+
+// TryLock is the preferred function for taking an exclusive file lock. This
+// function takes an RW-mutex lock before it tries to lock the file, so there
+// is the possibility that this function may block for a short time if another
+// goroutine is trying to take any action.
+//
+// The actual file lock is non-blocking. If we are unable to get the
+// exclusive file lock, the function will return false instead of waiting
+// for the lock. If we get the lock, we also set the *GFlock instance as
+// being exclusive-locked.
+func (f *GFlock) TryLock() (bool, error) {
+	return f.try(&f.l, false, filelock.TryLock)
+}
+
+// TryRLock is the preferred function for taking a shared file lock. This
+// function takes an RW-mutex lock before it tries to lock the file, so there
+// is the possibility that this function may block for a short time if another
+// goroutine is trying to take any action.
+//
+// The actual file lock is non-blocking. If we are unable to get the shared
+// file lock, the function will return false instead of waiting for the
+// lock. If we get the lock, we also set the *GFlock instance as being
+// share-locked.
+func (f *GFlock) TryRLock() (bool, error) {
+	return f.try(&f.r, false, filelock.TryRLock)
+}
+
+// tryLockWrite is TryLock, but forces the underlying descriptor to be
+// opened write-capable, for the same reason lockWrite does for Lock.
+func (f *GFlock) tryLockWrite() (bool, error) {
+	return f.try(&f.l, true, filelock.TryLock)
+}
+
+// tryRLockWrite is TryRLock, but forces the underlying descriptor to be
+// opened write-capable, for the same reason rlockWrite does for RLock.
+func (f *GFlock) tryRLockWrite() (bool, error) {
+	return f.try(&f.r, true, filelock.TryRLock)
+}
+
+func (f *GFlock) try(locked *bool, forceWrite bool, do func(filelock.File) (bool, error)) (bool, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if *locked {
+		return true, nil
+	}
+
+	if f.fh == nil {
+		if err := f.setFh(forceWrite); err != nil {
+			return false, err
+		}
+		defer f.ensureFhState()
+	}
+
+	ok, err := do(f.fh)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	*locked = true
+	return true, nil
+}
+
+// IsNotSupported reports whether err indicates that advisory locking is
+// not available for the relevant file or filesystem (for example, some
+// NFS or 9P mounts refuse fcntl/flock locking outright). Callers can use
+// this to detect the condition and fall back gracefully.
+func IsNotSupported(err error) bool {
+	return filelock.IsNotSupported(err)
+}
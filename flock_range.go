@@ -0,0 +1,306 @@
+package gflock
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tekintian/gflock/internal/filelock"
+)
+
+// rangeEOF is the sentinel end value used internally for a range whose
+// length is 0, meaning "to the end of the file": fcntl(F_SETLK) treats
+// Len==0 the same way, and representing it here as the largest possible
+// end keeps the interval math in mergeRange/splitRange from needing a
+// special case for it.
+const rangeEOF = math.MaxInt64
+
+// heldRange is a single non-overlapping, half-open byte interval
+// [start, end) currently locked on the file, tracked the same way the
+// kernel's own fcntl(F_SETLK) record locks are: placing an overlapping
+// lock replaces the overlapped portion, and unlocking a sub-range splits
+// whatever held it. f.ranges is kept in this normalized form at all
+// times so it never desyncs from the real lock state, even across
+// overlapping or nested LockRange/RLockRange calls on the same GFlock.
+type heldRange struct {
+	start, end int64
+	exclusive  bool
+}
+
+// Range describes a byte range held by a GFlock, as reported by Ranges.
+// A Length of 0 means the range extends to the end of the file.
+type Range struct {
+	Offset, Length int64
+	Exclusive      bool
+}
+
+// Ranges reports the byte ranges currently locked by f via LockRange or
+// RLockRange, along with whether each is held exclusively or shared.
+// It does not include the whole-file lock taken by Lock/RLock; use
+// Locked/RLocked for that.
+//
+// Warning: by the time you use the returned value, the state may have
+// changed.
+func (f *GFlock) Ranges() []Range {
+	f.m.RLock()
+	defer f.m.RUnlock()
+
+	out := make([]Range, len(f.ranges))
+	for i, hr := range f.ranges {
+		out[i] = Range{Offset: hr.start, Length: rangeLength(hr.start, hr.end), Exclusive: hr.exclusive}
+	}
+	return out
+}
+
+// LockRange is a blocking call to take an exclusive lock on the byte
+// range [offset, offset+length) of the file, leaving the rest of the
+// file free for other holders to lock. A length of 0 means "to the end
+// of the file". Lock is equivalent to LockRange(0, 0).
+//
+// Concurrent writers to disjoint regions of the same file (for example,
+// segment-based storage engines) can use this instead of locking the
+// whole file for every operation.
+//
+// Warning: any LockRange other than LockRange(0, 0), including one at
+// offset 0, does not exclude a concurrent whole-file Lock/RLock on the
+// same path, and vice versa; see the
+// package doc for why. Choose either whole-file or range locking for a
+// given file and stick to it.
+func (f *GFlock) LockRange(offset, length int64) error {
+	if offset == 0 && length == 0 {
+		// Go through lockWrite, not Lock: the fh this opens is reused by
+		// any later real LockRange/RLockRange on this GFlock (see the
+		// comment in lockRange), so it must already be write-capable even
+		// though a plain whole-file Lock would not otherwise need that.
+		return f.lockWrite()
+	}
+	return f.lockRange(offset, length, true, filelock.LockRange)
+}
+
+// RLockRange is a blocking call to take a shared lock on the byte range
+// [offset, offset+length) of the file. A length of 0 means "to the end
+// of the file". RLock is equivalent to RLockRange(0, 0).
+func (f *GFlock) RLockRange(offset, length int64) error {
+	if offset == 0 && length == 0 {
+		// See the matching comment in LockRange for why this is
+		// rlockWrite rather than RLock.
+		return f.rlockWrite()
+	}
+	return f.lockRange(offset, length, false, filelock.RLockRange)
+}
+
+// TryLockRange is the non-blocking form of LockRange: it returns false,
+// nil instead of waiting if the range is already locked elsewhere.
+func (f *GFlock) TryLockRange(offset, length int64) (bool, error) {
+	if offset == 0 && length == 0 {
+		// See the matching comment in LockRange for why this is
+		// tryLockWrite rather than TryLock.
+		return f.tryLockWrite()
+	}
+	return f.tryLockRange(offset, length, true, filelock.TryLockRange)
+}
+
+// TryRLockRange is the non-blocking form of RLockRange: it returns
+// false, nil instead of waiting if an incompatible lock on the range is
+// already held elsewhere.
+func (f *GFlock) TryRLockRange(offset, length int64) (bool, error) {
+	if offset == 0 && length == 0 {
+		// See the matching comment in LockRange for why this is
+		// tryRLockWrite rather than TryRLock.
+		return f.tryRLockWrite()
+	}
+	return f.tryLockRange(offset, length, false, filelock.TryRLockRange)
+}
+
+// TryLockRangeContext repeatedly tries to take an exclusive lock on the
+// byte range [offset, offset+length) until one of the conditions is met:
+// TryLockRange succeeds, TryLockRange fails with error, or Context Done
+// channel is closed.
+func (f *GFlock) TryLockRangeContext(ctx context.Context, offset, length int64, retryDelay time.Duration) (bool, error) {
+	return tryCtx(ctx, func() (bool, error) { return f.TryLockRange(offset, length) }, retryDelay)
+}
+
+// TryRLockRangeContext repeatedly tries to take a shared lock on the
+// byte range [offset, offset+length) until one of the conditions is met:
+// TryRLockRange succeeds, TryRLockRange fails with error, or Context Done
+// channel is closed.
+func (f *GFlock) TryRLockRangeContext(ctx context.Context, offset, length int64, retryDelay time.Duration) (bool, error) {
+	return tryCtx(ctx, func() (bool, error) { return f.TryRLockRange(offset, length) }, retryDelay)
+}
+
+func (f *GFlock) lockRange(offset, length int64, exclusive bool, do func(filelock.File, int64, int64) error) error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.fh == nil {
+		// Always open write-capable for ranges, regardless of whether
+		// this particular call is shared or exclusive: the same fh is
+		// reused for every later LockRange/RLockRange on this GFlock,
+		// and fcntl(F_SETLK) locks belong to the (process, inode) pair,
+		// not the fd, so closing this fh to reopen it write-capable
+		// later would silently drop any range lock already held on it.
+		if err := f.setFh(true); err != nil {
+			return err
+		}
+		defer f.ensureFhState()
+	}
+
+	if err := do(f.fh, offset, length); err != nil {
+		return err
+	}
+
+	f.ranges = mergeRange(f.ranges, offset, rangeEnd(offset, length), exclusive)
+	return nil
+}
+
+func (f *GFlock) tryLockRange(offset, length int64, exclusive bool, do func(filelock.File, int64, int64) (bool, error)) (bool, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.fh == nil {
+		// See the matching comment in lockRange for why this is always
+		// write-capable rather than gated on exclusive.
+		if err := f.setFh(true); err != nil {
+			return false, err
+		}
+		defer f.ensureFhState()
+	}
+
+	ok, err := do(f.fh, offset, length)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	f.ranges = mergeRange(f.ranges, offset, rangeEnd(offset, length), exclusive)
+	return true, nil
+}
+
+// UnlockRange releases whatever is locked over the byte range [offset,
+// offset+length), the same way fcntl(F_SETLK, F_UNLCK) does: it splits
+// any held range that only partially overlaps instead of requiring an
+// exact match against a prior LockRange/RLockRange call. It is a no-op
+// if nothing in that range is currently held. UnlockRange(0, 0) is the
+// same as Unlock: it releases the whole-file lock taken via Lock/RLock
+// along with every held range. Any other UnlockRange call leaves the
+// whole-file lock in place.
+func (f *GFlock) UnlockRange(offset, length int64) error {
+	if offset == 0 && length == 0 {
+		return f.Unlock()
+	}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	start, end := offset, rangeEnd(offset, length)
+	if !rangesOverlapAny(f.ranges, start, end) {
+		return nil
+	}
+
+	if f.fh == nil {
+		return nil
+	}
+
+	if err := filelock.UnlockRange(f.fh, offset, length); err != nil {
+		return err
+	}
+
+	f.ranges = splitRange(f.ranges, start, end)
+	f.ensureFhState()
+	return nil
+}
+
+// rangeEnd returns the exclusive end of [offset, offset+length), or
+// rangeEOF if length is 0 ("to the end of the file").
+func rangeEnd(offset, length int64) int64 {
+	if length == 0 {
+		return rangeEOF
+	}
+	return offset + length
+}
+
+// rangeLength is the inverse of rangeEnd: it recovers the Length to
+// report in a public Range from a start/end pair.
+func rangeLength(start, end int64) int64 {
+	if end == rangeEOF {
+		return 0
+	}
+	return end - start
+}
+
+func rangesOverlapAny(ranges []heldRange, start, end int64) bool {
+	for _, r := range ranges {
+		if r.start < end && start < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeRange folds a newly acquired lock on [start, end) into ranges,
+// the way the kernel's own fcntl record locks behave: any existing held
+// range that overlaps [start, end) has its overlapping portion replaced
+// by the new lock (splitting off whatever part of it falls outside
+// [start, end)), and the result is re-merged so adjacent or overlapping
+// entries of the same lock type collapse back into a single range.
+func mergeRange(ranges []heldRange, start, end int64, exclusive bool) []heldRange {
+	out := make([]heldRange, 0, len(ranges)+1)
+	for _, r := range ranges {
+		if r.end <= start || r.start >= end {
+			out = append(out, r)
+			continue
+		}
+		if r.start < start {
+			out = append(out, heldRange{start: r.start, end: start, exclusive: r.exclusive})
+		}
+		if r.end > end {
+			out = append(out, heldRange{start: end, end: r.end, exclusive: r.exclusive})
+		}
+	}
+	out = append(out, heldRange{start: start, end: end, exclusive: exclusive})
+	return normalizeRanges(out)
+}
+
+// splitRange removes [start, end) from ranges, splitting any held range
+// that only partially falls inside it, mirroring fcntl(F_UNLCK)'s own
+// splitting behavior.
+func splitRange(ranges []heldRange, start, end int64) []heldRange {
+	out := make([]heldRange, 0, len(ranges))
+	for _, r := range ranges {
+		if r.end <= start || r.start >= end {
+			out = append(out, r)
+			continue
+		}
+		if r.start < start {
+			out = append(out, heldRange{start: r.start, end: start, exclusive: r.exclusive})
+		}
+		if r.end > end {
+			out = append(out, heldRange{start: end, end: r.end, exclusive: r.exclusive})
+		}
+	}
+	return normalizeRanges(out)
+}
+
+// normalizeRanges sorts ranges by start and coalesces adjacent or
+// overlapping entries of the same lock type into one, so the result is
+// always a minimal set of non-overlapping intervals.
+func normalizeRanges(ranges []heldRange) []heldRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	out := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &out[len(out)-1]
+		if r.exclusive == last.exclusive && r.start <= last.end {
+			if r.end > last.end {
+				last.end = r.end
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
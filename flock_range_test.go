@@ -0,0 +1,188 @@
+package gflock_test
+
+import (
+	"os"
+
+	"github.com/tekintian/gflock"
+
+	. "gopkg.in/check.v1"
+)
+
+type RangeSuite struct {
+	path   string
+	gflock *gflock.GFlock
+}
+
+var _ = Suite(&RangeSuite{})
+
+func (s *RangeSuite) SetUpTest(c *C) {
+	tmpFile, err := os.CreateTemp(os.TempDir(), "gflock-range-")
+	c.Assert(err, IsNil)
+	s.path = tmpFile.Name()
+	defer os.Remove(s.path)
+	tmpFile.Close()
+
+	s.gflock = gflock.New(s.path)
+}
+
+func (s *RangeSuite) TearDownTest(c *C) {
+	s.gflock.Unlock()
+	os.Remove(s.path)
+}
+
+func (s *RangeSuite) TestLockRangeZeroIsWholeFileLock(c *C) {
+	c.Assert(s.gflock.LockRange(0, 0), IsNil)
+	c.Check(s.gflock.Locked(), Equals, true)
+	c.Check(s.gflock.Ranges(), HasLen, 0)
+}
+
+func (s *RangeSuite) TestRLockRangeZeroIsWholeFileRLock(c *C) {
+	c.Assert(s.gflock.RLockRange(0, 0), IsNil)
+	c.Check(s.gflock.RLocked(), Equals, true)
+	c.Check(s.gflock.Ranges(), HasLen, 0)
+}
+
+// TestLockRangeZeroThenRealRangeOnSameInstance guards against LockRange's
+// offset==0, length==0 case delegating to Lock, which opens the shared
+// fh read-only: a later real LockRange on the same instance reuses that
+// fh (see the comment in lockRange) and needs it write-capable for the
+// exclusive fcntl(F_SETLK) call to succeed.
+func (s *RangeSuite) TestLockRangeZeroThenRealRangeOnSameInstance(c *C) {
+	c.Assert(s.gflock.LockRange(0, 0), IsNil)
+	c.Assert(s.gflock.LockRange(10, 5), IsNil)
+
+	c.Check(s.gflock.Locked(), Equals, true)
+	c.Check(s.gflock.Ranges(), HasLen, 1)
+}
+
+// TestRLockRangeZeroThenRealRangeOnSameInstance is the RLockRange analog
+// of TestLockRangeZeroThenRealRangeOnSameInstance.
+func (s *RangeSuite) TestRLockRangeZeroThenRealRangeOnSameInstance(c *C) {
+	c.Assert(s.gflock.RLockRange(0, 0), IsNil)
+	c.Assert(s.gflock.LockRange(10, 5), IsNil)
+
+	c.Check(s.gflock.RLocked(), Equals, true)
+	c.Check(s.gflock.Ranges(), HasLen, 1)
+}
+
+func (s *RangeSuite) TestLockRangeTracksRange(c *C) {
+	c.Assert(s.gflock.LockRange(0, 10), IsNil)
+
+	ranges := s.gflock.Ranges()
+	c.Assert(ranges, HasLen, 1)
+	c.Check(ranges[0], Equals, gflock.Range{Offset: 0, Length: 10, Exclusive: true})
+	c.Check(s.gflock.Locked(), Equals, false)
+}
+
+func (s *RangeSuite) TestRLockRangeTracksRange(c *C) {
+	c.Assert(s.gflock.RLockRange(5, 15), IsNil)
+
+	ranges := s.gflock.Ranges()
+	c.Assert(ranges, HasLen, 1)
+	c.Check(ranges[0], Equals, gflock.Range{Offset: 5, Length: 15, Exclusive: false})
+}
+
+func (s *RangeSuite) TestTryLockRangeDisjointRangesBothSucceed(c *C) {
+	ok, err := s.gflock.TryLockRange(0, 10)
+	c.Assert(err, IsNil)
+	c.Check(ok, Equals, true)
+
+	ok, err = s.gflock.TryLockRange(20, 10)
+	c.Assert(err, IsNil)
+	c.Check(ok, Equals, true)
+
+	c.Check(s.gflock.Ranges(), HasLen, 2)
+}
+
+func (s *RangeSuite) TestUnlockRangeReleasesExactRange(c *C) {
+	c.Assert(s.gflock.LockRange(0, 10), IsNil)
+	c.Assert(s.gflock.LockRange(20, 10), IsNil)
+
+	c.Assert(s.gflock.UnlockRange(0, 10), IsNil)
+
+	ranges := s.gflock.Ranges()
+	c.Assert(ranges, HasLen, 1)
+	c.Check(ranges[0].Offset, Equals, int64(20))
+}
+
+func (s *RangeSuite) TestUnlockRangeNoOpWhenNotHeld(c *C) {
+	c.Assert(s.gflock.UnlockRange(0, 10), IsNil)
+	c.Check(s.gflock.Ranges(), HasLen, 0)
+}
+
+// TestLockRangeAfterRLockRangeOnSameInstance guards against setFh
+// opening the shared fh read-only for a GFlock's first range call: a
+// later exclusive LockRange on the same instance must still be able to
+// use it, since the fh is never reopened mid-lifetime.
+func (s *RangeSuite) TestLockRangeAfterRLockRangeOnSameInstance(c *C) {
+	c.Assert(s.gflock.RLockRange(0, 10), IsNil)
+	c.Assert(s.gflock.LockRange(20, 10), IsNil)
+
+	c.Check(s.gflock.Ranges(), HasLen, 2)
+}
+
+func (s *RangeSuite) TestUnlockReleasesAllRanges(c *C) {
+	c.Assert(s.gflock.LockRange(0, 10), IsNil)
+	c.Assert(s.gflock.RLockRange(20, 10), IsNil)
+
+	c.Assert(s.gflock.Unlock(), IsNil)
+	c.Check(s.gflock.Ranges(), HasLen, 0)
+}
+
+// TestLockRangeNestedSubRangeDoesNotLeakTheOuterRange guards against
+// f.ranges being a flat list of call records: fcntl(F_SETLK) locks are
+// keyed by (pid, inode) and merge/split by byte range, not by call
+// history, so taking a nested sub-range lock and then releasing it must
+// split the outer range rather than wiping out tracking for bytes the
+// nested call never touched.
+//
+// This only checks the Go-level interval bookkeeping on a single
+// *GFlock. It deliberately does not assert anything about a second
+// *GFlock on the same path: fcntl(F_SETLK) record locks are scoped per
+// (process, inode), not per file descriptor, so a second locker in this
+// same test process would never see contention regardless of whether
+// f.ranges is tracked correctly, making any such assertion unfalsifiable
+// here. Exercising real cross-process exclusion needs a second process.
+func (s *RangeSuite) TestLockRangeNestedSubRangeDoesNotLeakTheOuterRange(c *C) {
+	c.Assert(s.gflock.LockRange(0, 20), IsNil)
+	c.Assert(s.gflock.LockRange(5, 10), IsNil)
+
+	// The nested lock is the same exclusive type as the outer one, so
+	// the kernel (and our tracking) merges them back into one [0,20)
+	// range rather than two overlapping records.
+	ranges := s.gflock.Ranges()
+	c.Assert(ranges, HasLen, 1)
+	c.Check(ranges[0], Equals, gflock.Range{Offset: 0, Length: 20, Exclusive: true})
+
+	c.Assert(s.gflock.UnlockRange(5, 10), IsNil)
+
+	ranges = s.gflock.Ranges()
+	c.Assert(ranges, HasLen, 2)
+	c.Check(ranges[0], Equals, gflock.Range{Offset: 0, Length: 5, Exclusive: true})
+	c.Check(ranges[1], Equals, gflock.Range{Offset: 15, Length: 5, Exclusive: true})
+}
+
+// TestWholeFileAndRangeLocksDoNotExclude pins down the documented
+// non-interoperability between whole-file and byte-range locks on the
+// common POSIX backends (flock(2) vs fcntl(F_SETLK)): the two are
+// independent kernel lock tables, so a LockRange on a path that the same
+// process already holds exclusively via the whole-file Lock is expected
+// to succeed.
+//
+// That expected outcome is also what same-process fcntl permissiveness
+// would produce even if whole-file/range independence were broken, since
+// fcntl(F_SETLK) locks are scoped per (process, inode): a second locker
+// in this process never contends with the first regardless of range.
+// Verifying the real, cross-process independence this documents would
+// require a second process; this test only pins the single-process
+// behavior so regressions in that path are caught.
+func (s *RangeSuite) TestWholeFileAndRangeLocksDoNotExclude(c *C) {
+	c.Assert(s.gflock.Lock(), IsNil)
+
+	other := gflock.New(s.path)
+	defer other.Unlock()
+
+	ok, err := other.TryLockRange(0, 10)
+	c.Assert(err, IsNil)
+	c.Check(ok, Equals, true)
+}
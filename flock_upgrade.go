@@ -0,0 +1,100 @@
+package gflock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tekintian/gflock/internal/filelock"
+)
+
+// ErrNotLocked is returned by Upgrade and Downgrade when the GFlock does
+// not currently hold any lock to convert.
+var ErrNotLocked = errors.New("gflock: lock is not held")
+
+// Upgrade converts a currently-held shared (R) lock into an exclusive
+// lock. It does not call Unlock in between: on POSIX this is a single
+// flock()/fcntl() call on the already-open descriptor. With fcntl record
+// locks (Solaris, illumos, AIX) the kernel converts the lock atomically.
+// With flock(2) (the other POSIX platforms) the kernel removes the shared
+// lock before placing the exclusive one, so another waiter can take the
+// lock in between. See the package doc comment above for the caveat that some
+// UNIX-like systems may already transparently promote a shared lock to
+// exclusive on their own.
+//
+// Upgrade is idempotent: calling it while already exclusively locked is
+// a no-op. It returns ErrNotLocked if no whole-file lock (shared or
+// exclusive, via Lock/RLock) is currently held; a LockRange/RLockRange
+// held on the same GFlock does not count.
+func (f *GFlock) Upgrade() error {
+	_, err := f.upgrade(true)
+	return err
+}
+
+// UpgradeContext repeatedly tries to upgrade a held shared lock to an
+// exclusive one, retrying every retryDelay, until it succeeds or ctx is
+// done.
+func (f *GFlock) UpgradeContext(ctx context.Context, retryDelay time.Duration) error {
+	ok, err := tryCtx(ctx, func() (bool, error) { return f.upgrade(false) }, retryDelay)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (f *GFlock) upgrade(blocking bool) (bool, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.l {
+		return true, nil
+	}
+	if !f.r {
+		return false, ErrNotLocked
+	}
+
+	if blocking {
+		if err := filelock.Lock(f.fh); err != nil {
+			return false, err
+		}
+	} else {
+		ok, err := filelock.TryLock(f.fh)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	f.l = true
+	f.r = false
+	return true, nil
+}
+
+// Downgrade converts a currently-held exclusive lock into a shared (R)
+// lock with a single call on the held descriptor, as Upgrade does, and
+// with the same flock(2) caveat.
+//
+// Downgrade is idempotent: calling it while already share-locked is a
+// no-op. It returns ErrNotLocked if no whole-file lock is currently
+// held; a LockRange/RLockRange held on the same GFlock does not count.
+func (f *GFlock) Downgrade() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.r {
+		return nil
+	}
+	if !f.l {
+		return ErrNotLocked
+	}
+
+	if err := filelock.RLock(f.fh); err != nil {
+		return err
+	}
+
+	f.r = true
+	f.l = false
+	return nil
+}
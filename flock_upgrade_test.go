@@ -0,0 +1,91 @@
+package gflock_test
+
+import (
+	"os"
+
+	"github.com/tekintian/gflock"
+
+	. "gopkg.in/check.v1"
+)
+
+type UpgradeSuite struct {
+	path   string
+	gflock *gflock.GFlock
+}
+
+var _ = Suite(&UpgradeSuite{})
+
+func (s *UpgradeSuite) SetUpTest(c *C) {
+	tmpFile, err := os.CreateTemp(os.TempDir(), "gflock-upgrade-")
+	c.Assert(err, IsNil)
+	s.path = tmpFile.Name()
+	defer os.Remove(s.path)
+	tmpFile.Close()
+
+	s.gflock = gflock.New(s.path)
+}
+
+func (s *UpgradeSuite) TearDownTest(c *C) {
+	s.gflock.Unlock()
+	os.Remove(s.path)
+}
+
+func (s *UpgradeSuite) TestUpgradeRequiresRLock(c *C) {
+	err := s.gflock.Upgrade()
+	c.Assert(err, Equals, gflock.ErrNotLocked)
+}
+
+func (s *UpgradeSuite) TestDowngradeRequiresLock(c *C) {
+	err := s.gflock.Downgrade()
+	c.Assert(err, Equals, gflock.ErrNotLocked)
+}
+
+func (s *UpgradeSuite) TestUpgradeFromRLock(c *C) {
+	c.Assert(s.gflock.RLock(), IsNil)
+
+	c.Assert(s.gflock.Upgrade(), IsNil)
+	c.Check(s.gflock.Locked(), Equals, true)
+	c.Check(s.gflock.RLocked(), Equals, false)
+}
+
+func (s *UpgradeSuite) TestUpgradeIsIdempotent(c *C) {
+	c.Assert(s.gflock.Lock(), IsNil)
+	c.Assert(s.gflock.Upgrade(), IsNil)
+	c.Check(s.gflock.Locked(), Equals, true)
+}
+
+func (s *UpgradeSuite) TestDowngradeFromLock(c *C) {
+	c.Assert(s.gflock.Lock(), IsNil)
+
+	c.Assert(s.gflock.Downgrade(), IsNil)
+	c.Check(s.gflock.Locked(), Equals, false)
+	c.Check(s.gflock.RLocked(), Equals, true)
+}
+
+func (s *UpgradeSuite) TestDowngradeIsIdempotent(c *C) {
+	c.Assert(s.gflock.RLock(), IsNil)
+	c.Assert(s.gflock.Downgrade(), IsNil)
+	c.Check(s.gflock.RLocked(), Equals, true)
+}
+
+// TestUpgradeRequiresWholeFileLock guards against using fd-nilness as a
+// stand-in for lock state: a GFlock that only holds a LockRange, not a
+// whole-file RLock, must not have Upgrade silently take a whole-file
+// lock that was never requested.
+func (s *UpgradeSuite) TestUpgradeRequiresWholeFileLock(c *C) {
+	c.Assert(s.gflock.RLockRange(0, 4), IsNil)
+
+	err := s.gflock.Upgrade()
+	c.Assert(err, Equals, gflock.ErrNotLocked)
+	c.Check(s.gflock.Locked(), Equals, false)
+}
+
+// TestDowngradeRequiresWholeFileLock is the Downgrade analogue of
+// TestUpgradeRequiresWholeFileLock.
+func (s *UpgradeSuite) TestDowngradeRequiresWholeFileLock(c *C) {
+	c.Assert(s.gflock.LockRange(0, 4), IsNil)
+
+	err := s.gflock.Downgrade()
+	c.Assert(err, Equals, gflock.ErrNotLocked)
+	c.Check(s.gflock.RLocked(), Equals, false)
+}
@@ -0,0 +1,135 @@
+// Package filelock provides platform-independent advisory file locking,
+// factored out of the gflock package the same way the Go toolchain
+// factors cmd/go/internal/lockedfile/internal/filelock out of
+// cmd/go/internal/lockedfile: each supported platform gets its own
+// lock/rlock/trylock/unlock implementation file selected by build tags,
+// and the rest of the module only ever calls the exported functions
+// below.
+//
+// WARNING: on the common POSIX platforms (linux, freebsd, netbsd,
+// openbsd, dragonfly, darwin), Lock/RLock/TryLock/TryRLock/Unlock are
+// implemented with flock(2), while LockRange/RLockRange/TryLockRange/
+// TryRLockRange/UnlockRange are implemented with fcntl(F_SETLK). These
+// are independent kernel lock tables that do not exclude one another
+// (see flock(2) NOTES): a whole-file Lock() and a LockRange() on the
+// same path, even covering the same bytes, will never conflict with
+// each other. Do not mix whole-file and range calls on the same file
+// and expect them to interoperate; pick one family and use it
+// consistently for a given path. Solaris, illumos and AIX do not have
+// this hazard, since both families already go through fcntl there.
+package filelock
+
+// File is the subset of *os.File that the locking implementations need.
+type File interface {
+	// Fd returns the file's underlying descriptor or handle.
+	Fd() uintptr
+}
+
+// Lock places an exclusive lock on f, blocking until it is available.
+func Lock(f File) error {
+	return lock(f, writeLock)
+}
+
+// RLock places a shared lock on f, blocking until it is available.
+func RLock(f File) error {
+	return lock(f, readLock)
+}
+
+// TryLock attempts to place an exclusive lock on f without blocking. It
+// reports false, nil if the lock is already held elsewhere.
+func TryLock(f File) (bool, error) {
+	return tryLock(f, writeLock)
+}
+
+// TryRLock attempts to place a shared lock on f without blocking. It
+// reports false, nil if an incompatible lock is already held elsewhere.
+func TryRLock(f File) (bool, error) {
+	return tryLock(f, readLock)
+}
+
+// Unlock releases whatever lock Lock, RLock, TryLock or TryRLock placed
+// on f.
+func Unlock(f File) error {
+	return unlock(f)
+}
+
+// LockRange places an exclusive lock on the byte range [offset,
+// offset+length) of f, blocking until it is available. A length of 0
+// means "to the end of the file", matching POSIX fcntl(F_SETLK)
+// semantics.
+func LockRange(f File, offset, length int64) error {
+	return lockRange(f, writeLock, offset, length)
+}
+
+// RLockRange places a shared lock on the byte range [offset,
+// offset+length) of f, blocking until it is available.
+func RLockRange(f File, offset, length int64) error {
+	return lockRange(f, readLock, offset, length)
+}
+
+// TryLockRange attempts to place an exclusive lock on the byte range
+// [offset, offset+length) of f without blocking. It reports false, nil
+// if the range is already locked elsewhere.
+func TryLockRange(f File, offset, length int64) (bool, error) {
+	return tryLockRange(f, writeLock, offset, length)
+}
+
+// TryRLockRange attempts to place a shared lock on the byte range
+// [offset, offset+length) of f without blocking. It reports false, nil
+// if an incompatible lock on the range is already held elsewhere.
+func TryRLockRange(f File, offset, length int64) (bool, error) {
+	return tryLockRange(f, readLock, offset, length)
+}
+
+// UnlockRange releases whatever lock LockRange, RLockRange,
+// TryLockRange or TryRLockRange placed on the byte range [offset,
+// offset+length) of f.
+func UnlockRange(f File, offset, length int64) error {
+	return unlockRange(f, offset, length)
+}
+
+type lockType int8
+
+const (
+	readLock lockType = iota + 1
+	writeLock
+)
+
+// notSupportedError wraps an underlying error to mark a filesystem or
+// platform as not implementing advisory locking, so that IsNotSupported
+// can recognize it regardless of backend.
+type notSupportedError struct {
+	err error
+}
+
+func (e *notSupportedError) Error() string      { return e.err.Error() }
+func (e *notSupportedError) Unwrap() error      { return e.err }
+func (e *notSupportedError) NotSupported() bool { return true }
+
+// IsNotSupported reports whether err indicates that advisory locking is
+// not available for the relevant file or filesystem (for example, some
+// NFS or 9P mounts refuse fcntl/flock locking outright). Callers can use
+// this to fall back to an unlocked code path instead of failing outright.
+func IsNotSupported(err error) bool {
+	type notSupported interface {
+		NotSupported() bool
+	}
+
+	for {
+		if ns, ok := err.(notSupported); ok {
+			return ns.NotSupported()
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+		if err == nil {
+			return false
+		}
+	}
+}
+
+func errNotSupported(err error) error {
+	return &notSupportedError{err: err}
+}
@@ -0,0 +1,61 @@
+//go:build solaris || illumos || aix
+// +build solaris illumos aix
+
+package filelock
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenFlags reports the os.OpenFile flags that a caller should use when
+// opening a file it intends to lock with this backend. Unlike flock(2),
+// POSIX fcntl(F_SETLK) record locks are tied to the access mode the
+// descriptor was opened with: a write (exclusive) lock requires a
+// descriptor opened for writing. Since a single *os.File may be used for
+// either a Lock or an RLock, the descriptor is always opened read-write
+// so that both are possible.
+const OpenFlags = os.O_RDWR
+
+func lock(f File, lt lockType) error {
+	return wrapNotSupported(fcntlLock(f, lt, true))
+}
+
+func tryLock(f File, lt lockType) (bool, error) {
+	err := fcntlLock(f, lt, false)
+	switch {
+	case err == nil:
+		return true, nil
+	case err == syscall.EACCES || err == syscall.EAGAIN:
+		return false, nil
+	default:
+		return false, wrapNotSupported(err)
+	}
+}
+
+func unlock(f File) error {
+	flock := syscall.Flock_t{
+		Type:   syscall.F_UNLCK,
+		Whence: int16(os.SEEK_SET),
+	}
+	return wrapNotSupported(syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &flock))
+}
+
+func fcntlLock(f File, lt lockType, blocking bool) error {
+	typ := int16(syscall.F_RDLCK)
+	if lt == writeLock {
+		typ = syscall.F_WRLCK
+	}
+
+	flock := syscall.Flock_t{
+		Type:   typ,
+		Whence: int16(os.SEEK_SET),
+	}
+
+	cmd := syscall.F_SETLK
+	if blocking {
+		cmd = syscall.F_SETLKW
+	}
+
+	return syscall.FcntlFlock(f.Fd(), cmd, &flock)
+}
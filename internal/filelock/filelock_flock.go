@@ -0,0 +1,50 @@
+//go:build linux || freebsd || netbsd || openbsd || dragonfly || darwin
+// +build linux freebsd netbsd openbsd dragonfly darwin
+
+package filelock
+
+import (
+	"errors"
+	"syscall"
+)
+
+// OpenFlags reports the os.OpenFile flags that a caller should use when
+// opening a file it intends to lock with this backend. The BSD flock
+// syscall locks a description, not a mode, so a read-only descriptor is
+// enough for both read and write locks.
+const OpenFlags = 0
+
+func lock(f File, lt lockType) error {
+	return wrapNotSupported(syscall.Flock(int(f.Fd()), flockOp(lt)))
+}
+
+func tryLock(f File, lt lockType) (bool, error) {
+	var retried bool
+retry:
+	err := syscall.Flock(int(f.Fd()), flockOp(lt)|syscall.LOCK_NB)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, syscall.EWOULDBLOCK):
+		return false, nil
+	case errors.Is(err, syscall.EINTR):
+		if !retried {
+			retried = true
+			goto retry
+		}
+		return false, err
+	default:
+		return false, wrapNotSupported(err)
+	}
+}
+
+func unlock(f File) error {
+	return wrapNotSupported(syscall.Flock(int(f.Fd()), syscall.LOCK_UN))
+}
+
+func flockOp(lt lockType) int {
+	if lt == writeLock {
+		return syscall.LOCK_EX
+	}
+	return syscall.LOCK_SH
+}
@@ -0,0 +1,28 @@
+//go:build !linux && !freebsd && !netbsd && !openbsd && !dragonfly && !darwin && !solaris && !illumos && !aix && !windows && !plan9
+// +build !linux,!freebsd,!netbsd,!openbsd,!dragonfly,!darwin,!solaris,!illumos,!aix,!windows,!plan9
+
+package filelock
+
+import "errors"
+
+// OpenFlags reports the os.OpenFile flags that a caller should use when
+// opening a file it intends to lock with this backend.
+const OpenFlags = 0
+
+// errNotSupportedOther is returned by every operation in this file: this
+// platform (e.g. js/wasm) has no known advisory locking syscall, so
+// gflock falls back to reporting it as unsupported rather than silently
+// no-oping.
+var errNotSupportedOther = errors.New("filelock: not supported on this platform")
+
+func lock(f File, lt lockType) error {
+	return errNotSupported(errNotSupportedOther)
+}
+
+func tryLock(f File, lt lockType) (bool, error) {
+	return false, errNotSupported(errNotSupportedOther)
+}
+
+func unlock(f File) error {
+	return errNotSupported(errNotSupportedOther)
+}
@@ -0,0 +1,27 @@
+//go:build plan9
+// +build plan9
+
+package filelock
+
+import "errors"
+
+// OpenFlags reports the os.OpenFile flags that a caller should use when
+// opening a file it intends to lock with this backend.
+const OpenFlags = 0
+
+// errNotSupportedPlan9 is returned by every operation in this file: Plan 9
+// has no advisory record-locking syscall equivalent to flock(2) or
+// fcntl(F_SETLK), so gflock cannot offer real cross-process locking here.
+var errNotSupportedPlan9 = errors.New("filelock: not supported on plan9")
+
+func lock(f File, lt lockType) error {
+	return errNotSupported(errNotSupportedPlan9)
+}
+
+func tryLock(f File, lt lockType) (bool, error) {
+	return false, errNotSupported(errNotSupportedPlan9)
+}
+
+func unlock(f File) error {
+	return errNotSupported(errNotSupportedPlan9)
+}
@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+// +build !unix,!windows
+
+package filelock
+
+func lockRange(f File, lt lockType, offset, length int64) error {
+	return lock(f, lt)
+}
+
+func tryLockRange(f File, lt lockType, offset, length int64) (bool, error) {
+	return tryLock(f, lt)
+}
+
+func unlockRange(f File, offset, length int64) error {
+	return unlock(f)
+}
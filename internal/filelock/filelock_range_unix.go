@@ -0,0 +1,80 @@
+//go:build unix
+
+package filelock
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockRange and its siblings implement byte-range locking for every
+// POSIX platform via fcntl(F_SETLK), since flock(2) (used by lock/unlock
+// in filelock_flock.go on the common platforms) only ever locks a whole file. A length
+// of 0 means "to the end of the file", matching the Flock_t.Len=0 POSIX
+// convention.
+func lockRange(f File, lt lockType, offset, length int64) error {
+	return wrapNotSupported(fcntlLockRange(f, lt, offset, length, true))
+}
+
+func tryLockRange(f File, lt lockType, offset, length int64) (bool, error) {
+	err := fcntlLockRange(f, lt, offset, length, false)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EAGAIN):
+		return false, nil
+	default:
+		return false, wrapNotSupported(err)
+	}
+}
+
+func unlockRange(f File, offset, length int64) error {
+	flock := syscall.Flock_t{
+		Type:   syscall.F_UNLCK,
+		Whence: int16(os.SEEK_SET),
+		Start:  offset,
+		Len:    length,
+	}
+	return wrapNotSupported(syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &flock))
+}
+
+func fcntlLockRange(f File, lt lockType, offset, length int64, blocking bool) error {
+	typ := int16(syscall.F_RDLCK)
+	if lt == writeLock {
+		typ = syscall.F_WRLCK
+	}
+
+	flock := syscall.Flock_t{
+		Type:   typ,
+		Whence: int16(os.SEEK_SET),
+		Start:  offset,
+		Len:    length,
+	}
+
+	cmd := syscall.F_SETLK
+	if blocking {
+		cmd = syscall.F_SETLKW
+	}
+
+	return syscall.FcntlFlock(f.Fd(), cmd, &flock)
+}
+
+// wrapNotSupported classifies the errno a POSIX lock syscall returns when
+// the underlying filesystem doesn't actually implement advisory locking
+// (for example, an NFS mount exported without lockd, or without fcntl
+// locking support at all) as a notSupportedError, so that IsNotSupported
+// can recognize it regardless of whether the whole-file lock/unlock
+// above this build tag go through flock(2) or fcntl(F_SETLK); every
+// other error is returned unchanged. It lives here rather than in
+// filelock_flock.go/filelock_fcntl.go because this file's "unix" build
+// tag is a superset of both of theirs.
+func wrapNotSupported(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, syscall.ENOLCK) || errors.Is(err, syscall.EOPNOTSUPP) {
+		return errNotSupported(err)
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+//go:build windows
+// +build windows
+
+package filelock
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// maxDWORD used as both the length-low and length-high fields tells
+// LockFileEx/UnlockFileEx to lock through to the end of the file (and
+// keep locking it as it grows), matching the "length 0 means to EOF"
+// convention used by the POSIX backend.
+const maxDWORD = 0xFFFFFFFF
+
+func lockRange(f File, lt lockType, offset, length int64) error {
+	return lockFileExRange(f, lockFlags(lt), offset, length)
+}
+
+func tryLockRange(f File, lt lockType, offset, length int64) (bool, error) {
+	err := lockFileExRange(f, lockFlags(lt)|flagFailImmediately, offset, length)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, errorLockViolation):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func unlockRange(f File, offset, length int64) error {
+	low, high := rangeLowHigh(length)
+	ol := overlappedFor(offset)
+	r1, _, errNo := procUnlockFileEx.Call(f.Fd(), 0, uintptr(low), uintptr(high), uintptr(unsafe.Pointer(ol)))
+	if r1 == 0 {
+		if errNo != syscall.Errno(0) {
+			return errNo
+		}
+		return syscall.EINVAL
+	}
+	return nil
+}
+
+func lockFileExRange(f File, flags uint32, offset, length int64) error {
+	low, high := rangeLowHigh(length)
+	ol := overlappedFor(offset)
+	r1, _, errNo := procLockFileEx.Call(f.Fd(), uintptr(flags), 0, uintptr(low), uintptr(high), uintptr(unsafe.Pointer(ol)))
+	if r1 == 0 {
+		if errNo != syscall.Errno(0) {
+			return errNo
+		}
+		return syscall.EINVAL
+	}
+	return nil
+}
+
+func rangeLowHigh(length int64) (uint32, uint32) {
+	if length == 0 {
+		return maxDWORD, maxDWORD
+	}
+	return uint32(length), uint32(length >> 32)
+}
+
+func overlappedFor(offset int64) *syscall.Overlapped {
+	return &syscall.Overlapped{
+		Offset:     uint32(offset),
+		OffsetHigh: uint32(offset >> 32),
+	}
+}
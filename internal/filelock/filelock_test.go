@@ -0,0 +1,33 @@
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsNotSupported(t *testing.T) {
+	base := errors.New("locking not available")
+	err := errNotSupported(base)
+
+	if !IsNotSupported(err) {
+		t.Fatalf("IsNotSupported(%v) = false, want true", err)
+	}
+	if !errors.Is(err, base) {
+		t.Fatalf("errNotSupported(%v) does not unwrap to the wrapped error", err)
+	}
+
+	wrapped := fmt.Errorf("open lockfile: %w", err)
+	if !IsNotSupported(wrapped) {
+		t.Fatalf("IsNotSupported(%v) = false, want true for a further-wrapped error", wrapped)
+	}
+}
+
+func TestIsNotSupportedFalseForOrdinaryErrors(t *testing.T) {
+	if IsNotSupported(errors.New("some other failure")) {
+		t.Fatal("IsNotSupported reported an ordinary error as unsupported")
+	}
+	if IsNotSupported(nil) {
+		t.Fatal("IsNotSupported reported nil as unsupported")
+	}
+}
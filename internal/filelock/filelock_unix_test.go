@@ -0,0 +1,27 @@
+//go:build unix
+
+package filelock
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestWrapNotSupportedClassifiesLockErrnos(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"ENOLCK", syscall.ENOLCK, true},
+		{"EOPNOTSUPP", syscall.EOPNOTSUPP, true},
+		{"EACCES", syscall.EACCES, false},
+		{"nil", nil, false},
+	}
+
+	for _, tc := range cases {
+		if got := IsNotSupported(wrapNotSupported(tc.err)); got != tc.want {
+			t.Errorf("%s: IsNotSupported(wrapNotSupported(%v)) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+//go:build windows
+// +build windows
+
+package filelock
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// OpenFlags reports the os.OpenFile flags that a caller should use when
+// opening a file it intends to lock with this backend.
+const OpenFlags = 0
+
+var (
+	modkernel32      = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	flagFailImmediately = 0x00000001
+	flagExclusiveLock   = 0x00000002
+
+	// errorLockViolation is the Win32 ERROR_LOCK_VIOLATION code, returned by
+	// LockFileEx when the requested range is already locked by someone else.
+	// It isn't exposed by the standard syscall package on windows, so it's
+	// named here rather than pulled in from golang.org/x/sys/windows.
+	errorLockViolation = syscall.Errno(0x21)
+)
+
+func lock(f File, lt lockType) error {
+	return lockFileEx(f, lockFlags(lt))
+}
+
+func tryLock(f File, lt lockType) (bool, error) {
+	err := lockFileEx(f, lockFlags(lt)|flagFailImmediately)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, errorLockViolation):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func unlock(f File) error {
+	ol := new(syscall.Overlapped)
+	r1, _, errNo := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r1 == 0 {
+		if errNo != syscall.Errno(0) {
+			return errNo
+		}
+		return syscall.EINVAL
+	}
+	return nil
+}
+
+func lockFileEx(f File, flags uint32) error {
+	ol := new(syscall.Overlapped)
+	r1, _, errNo := procLockFileEx.Call(f.Fd(), uintptr(flags), 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r1 == 0 {
+		if errNo != syscall.Errno(0) {
+			return errNo
+		}
+		return syscall.EINVAL
+	}
+	return nil
+}
+
+func lockFlags(lt lockType) uint32 {
+	if lt == writeLock {
+		return flagExclusiveLock
+	}
+	return 0
+}
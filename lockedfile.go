@@ -0,0 +1,217 @@
+package gflock
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// LockedFile is a file handle that holds a GFlock for as long as it is
+// open. It embeds *os.File so that callers can use it like a regular file,
+// and releases the underlying lock when Close is called.
+type LockedFile struct {
+	*os.File
+
+	flock *GFlock
+}
+
+// OpenFile is like os.OpenFile, but it also takes a GFlock on the
+// resulting file before returning it. If flag includes os.O_WRONLY or
+// os.O_RDWR, the file is exclusively locked; otherwise it is locked for
+// shared (read-only) access.
+//
+// The lock is released when the returned *LockedFile is closed.
+func OpenFile(path string, flag int, perm os.FileMode) (*LockedFile, error) {
+	if flag&os.O_CREATE != 0 {
+		if err := createIfNotExist(path, perm); err != nil {
+			return nil, err
+		}
+	} else if _, err := os.Stat(path); err != nil {
+		// GFlock.RLock/Lock create the lockfile as a side effect of
+		// taking the lock (see setFh), so without this check a missing
+		// path would come back as a successful, silently-created empty
+		// file instead of the error os.OpenFile itself would give.
+		return nil, err
+	}
+
+	fl := New(path)
+
+	var lockErr error
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		lockErr = fl.Lock()
+	} else {
+		lockErr = fl.RLock()
+	}
+	if lockErr != nil {
+		return nil, lockErr
+	}
+
+	fh, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		fl.Unlock()
+		return nil, err
+	}
+
+	return &LockedFile{File: fh, flock: fl}, nil
+}
+
+// Open is like os.Open, but it also takes a shared GFlock on the resulting
+// file before returning it. The lock is released when the returned
+// *LockedFile is closed.
+func Open(path string) (*LockedFile, error) {
+	return OpenFile(path, os.O_RDONLY, 0)
+}
+
+// Create is like os.Create, but it also takes an exclusive GFlock on the
+// resulting file before returning it. The lock is released when the
+// returned *LockedFile is closed.
+func Create(path string) (*LockedFile, error) {
+	return OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// Close closes the underlying file and releases the lock taken in
+// OpenFile/Open/Create.
+func (f *LockedFile) Close() error {
+	closeErr := f.File.Close()
+	if err := f.flock.Unlock(); err != nil && closeErr == nil {
+		closeErr = err
+	}
+	return closeErr
+}
+
+// Read takes a shared lock on path, reads its complete contents, then
+// releases the lock. It is a convenience wrapper around Open for callers
+// that don't need to keep the file open.
+func Read(path string) ([]byte, error) {
+	f, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f.File)
+}
+
+// Write takes an exclusive lock on path, then writes content to it in
+// place through the locked descriptor, before releasing the lock.
+//
+// Writing in place, rather than renaming a new inode over path, matters
+// here: a rename would swap path over to an inode the lock was never
+// taken on, so a concurrent caller opening path after the rename but
+// before this Write's Unlock runs would acquire its own lock on the new
+// inode with zero real contention, silently losing whichever write lost
+// the race.
+func Write(path string, content []byte, perm os.FileMode) (err error) {
+	if err := createIfNotExist(path, perm); err != nil {
+		return err
+	}
+
+	fl := New(path)
+	if err := fl.lockWrite(); err != nil {
+		return err
+	}
+	defer fl.Unlock()
+
+	return writeInPlace(fl.fh, content, perm)
+}
+
+// Transform invokes fn with the content of the file at path, read-locking
+// the file for the duration of the read, then upgrading to an exclusive
+// lock (see Upgrade) to write back whatever fn returns through that same
+// locked descriptor (see Write for why this can't be a rename). If fn
+// returns an error, the file is left unmodified and that error is
+// returned.
+//
+// Holding only a shared lock while fn runs means another Transform
+// racing against this one can upgrade, write, and unlock before our own
+// Upgrade below returns, so the content fn saw may already be stale by
+// the time we're about to write. Once Upgrade returns, though, nothing
+// else can write until we unlock, so a single re-read at that point is
+// enough to detect this and re-run fn against the current content
+// instead of clobbering a concurrent update with stale data.
+func Transform(path string, fn func([]byte) ([]byte, error)) (err error) {
+	fl := New(path)
+	if err := fl.rlockWrite(); err != nil {
+		return err
+	}
+
+	old, err := readAllPath(path)
+	if err != nil {
+		fl.Unlock()
+		return err
+	}
+
+	updated, err := fn(old)
+	if err != nil {
+		fl.Unlock()
+		return err
+	}
+
+	if err := fl.Upgrade(); err != nil {
+		fl.Unlock()
+		return err
+	}
+	defer fl.Unlock()
+
+	current, err := readAllPath(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, old) {
+		updated, err = fn(current)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeInPlace(fl.fh, updated, 0666)
+}
+
+// createIfNotExist creates path with perm if it does not already exist,
+// then closes it. This must run before the GFlock is taken: GFlock's own
+// setFh creates the lockfile with a hardcoded, non-configurable mode if
+// it doesn't exist yet, which would otherwise win the race against
+// os.OpenFile's O_CREATE and silently discard the caller's perm.
+func createIfNotExist(path string, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+	return f.Close()
+}
+
+func readAllPath(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// writeInPlace truncates fh and writes content to it from the start, the
+// way Go's own cmd/go/internal/lockedfile does: through the descriptor
+// the caller already holds the lock on, rather than renaming a new inode
+// over the locked path (see Write).
+func writeInPlace(fh *os.File, content []byte, perm os.FileMode) error {
+	if err := fh.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := fh.Write(content); err != nil {
+		return err
+	}
+	if err := fh.Chmod(perm); err != nil {
+		return err
+	}
+	return fh.Sync()
+}
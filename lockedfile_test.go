@@ -0,0 +1,168 @@
+package gflock_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/tekintian/gflock"
+
+	. "gopkg.in/check.v1"
+)
+
+type LockedFileSuite struct {
+	path string
+}
+
+var _ = Suite(&LockedFileSuite{})
+
+func (s *LockedFileSuite) SetUpTest(c *C) {
+	s.path = filepath.Join(c.MkDir(), "locked-file")
+}
+
+func (s *LockedFileSuite) TestOpenFileExclusive(c *C) {
+	f, err := gflock.OpenFile(s.path, os.O_RDWR|os.O_CREATE, 0600)
+	c.Assert(err, IsNil)
+
+	_, err = f.WriteString("hello")
+	c.Assert(err, IsNil)
+
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *LockedFileSuite) TestOpenFileHonorsPerm(c *C) {
+	f, err := gflock.OpenFile(s.path, os.O_RDWR|os.O_CREATE, 0640)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	info, err := os.Stat(s.path)
+	c.Assert(err, IsNil)
+	c.Check(info.Mode().Perm(), Equals, os.FileMode(0640))
+}
+
+func (s *LockedFileSuite) TestOpenMissingFileErrors(c *C) {
+	_, err := gflock.Open(s.path)
+	c.Assert(err, NotNil)
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	_, statErr := os.Stat(s.path)
+	c.Check(os.IsNotExist(statErr), Equals, true)
+}
+
+func (s *LockedFileSuite) TestReadMissingFileErrors(c *C) {
+	content, err := gflock.Read(s.path)
+	c.Assert(err, NotNil)
+	c.Check(os.IsNotExist(err), Equals, true)
+	c.Check(content, IsNil)
+
+	_, statErr := os.Stat(s.path)
+	c.Check(os.IsNotExist(statErr), Equals, true)
+}
+
+func (s *LockedFileSuite) TestOpenReadOnly(c *C) {
+	c.Assert(gflock.Write(s.path, []byte("hello"), 0600), IsNil)
+
+	f, err := gflock.Open(s.path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	buf := make([]byte, 5)
+	_, err = f.Read(buf)
+	c.Assert(err, IsNil)
+	c.Check(string(buf), Equals, "hello")
+}
+
+func (s *LockedFileSuite) TestCreateTruncates(c *C) {
+	c.Assert(gflock.Write(s.path, []byte("old content"), 0600), IsNil)
+
+	f, err := gflock.Create(s.path)
+	c.Assert(err, IsNil)
+	_, err = f.WriteString("new")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	content, err := gflock.Read(s.path)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "new")
+}
+
+func (s *LockedFileSuite) TestWriteThenRead(c *C) {
+	c.Assert(gflock.Write(s.path, []byte("payload"), 0600), IsNil)
+
+	content, err := gflock.Read(s.path)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "payload")
+}
+
+func (s *LockedFileSuite) TestTransform(c *C) {
+	c.Assert(gflock.Write(s.path, []byte("1"), 0600), IsNil)
+
+	err := gflock.Transform(s.path, func(old []byte) ([]byte, error) {
+		c.Check(string(old), Equals, "1")
+		return []byte("2"), nil
+	})
+	c.Assert(err, IsNil)
+
+	content, err := gflock.Read(s.path)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "2")
+}
+
+func (s *LockedFileSuite) TestTransformOnMissingFile(c *C) {
+	err := gflock.Transform(s.path, func(old []byte) ([]byte, error) {
+		c.Check(len(old), Equals, 0)
+		return []byte("created"), nil
+	})
+	c.Assert(err, IsNil)
+
+	content, err := gflock.Read(s.path)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "created")
+}
+
+// TestTransformConcurrentIncrementsDoNotLoseUpdates guards against
+// Transform (and Write, which it shares writeInPlace with) writing back
+// by renaming a freshly created inode over path instead of through the
+// descriptor the lock was actually taken on: a rename there would let a
+// concurrent caller open the new inode and acquire its own lock with no
+// real contention, silently dropping whichever update lost the race.
+func (s *LockedFileSuite) TestTransformConcurrentIncrementsDoNotLoseUpdates(c *C) {
+	c.Assert(gflock.Write(s.path, []byte("0"), 0600), IsNil)
+
+	const n = 200
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			err := gflock.Transform(s.path, func(old []byte) ([]byte, error) {
+				cur, err := strconv.Atoi(string(old))
+				if err != nil {
+					return nil, err
+				}
+				return []byte(strconv.Itoa(cur + 1)), nil
+			})
+			c.Check(err, IsNil)
+		}()
+	}
+	wg.Wait()
+
+	content, err := gflock.Read(s.path)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, strconv.Itoa(n))
+}
+
+func (s *LockedFileSuite) TestTransformLeavesFileOnError(c *C) {
+	c.Assert(gflock.Write(s.path, []byte("unchanged"), 0600), IsNil)
+
+	wantErr := os.ErrInvalid
+	err := gflock.Transform(s.path, func(old []byte) ([]byte, error) {
+		return nil, wantErr
+	})
+	c.Assert(err, Equals, wantErr)
+
+	content, err := gflock.Read(s.path)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "unchanged")
+}
@@ -0,0 +1,115 @@
+package gflock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Mutex provides a sync.Mutex-shaped API for cross-process coordination,
+// backed by a GFlock at path. Unlike GFlock, it never exposes a file
+// handle: Lock and RLock return an unlock closure so that callers can
+// write the familiar `defer unlock()` pattern instead of tracking lock
+// state themselves and risking a double-unlock.
+//
+// A *Mutex is also safe for ordinary same-process concurrent use by
+// multiple goroutines, unlike a bare *GFlock: Lock/RLock/LockContext/
+// RLockContext first serialize through an in-process sync.RWMutex before
+// ever touching the file lock, so two goroutines racing to Lock() the
+// same *Mutex block against each other exactly as they would with a
+// sync.Mutex, rather than both observing the file as already locked and
+// proceeding concurrently.
+type Mutex struct {
+	mu    sync.RWMutex
+	flock *GFlock
+}
+
+// NewMutex returns a new *Mutex backed by the lockfile at path.
+func NewMutex(path string) *Mutex {
+	return &Mutex{flock: New(path)}
+}
+
+// Lock blocks until it acquires an exclusive lock on the underlying
+// lockfile, then returns a closure that releases it. Calling unlock more
+// than once is safe; calls after the first are no-ops.
+func (m *Mutex) Lock() (unlock func(), err error) {
+	m.mu.Lock()
+	if err := m.flock.Lock(); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	return m.unlockOnce(m.mu.Unlock), nil
+}
+
+// RLock blocks until it acquires a shared lock on the underlying
+// lockfile, then returns a closure that releases it. Calling unlock more
+// than once is safe; calls after the first are no-ops.
+func (m *Mutex) RLock() (unlock func(), err error) {
+	m.mu.RLock()
+	if err := m.flock.RLock(); err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	return m.unlockOnce(m.mu.RUnlock), nil
+}
+
+// LockContext repeatedly tries to acquire an exclusive lock, retrying
+// every retry interval, until it succeeds or ctx is done.
+func (m *Mutex) LockContext(ctx context.Context, retry time.Duration) (unlock func(), err error) {
+	ok, err := tryCtx(ctx, func() (bool, error) { return m.mu.TryLock(), nil }, retry)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ctx.Err()
+	}
+
+	ok, err = m.flock.TryLockContext(ctx, retry)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	if !ok {
+		m.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	return m.unlockOnce(m.mu.Unlock), nil
+}
+
+// RLockContext repeatedly tries to acquire a shared lock, retrying every
+// retry interval, until it succeeds or ctx is done.
+func (m *Mutex) RLockContext(ctx context.Context, retry time.Duration) (unlock func(), err error) {
+	ok, err := tryCtx(ctx, func() (bool, error) { return m.mu.TryRLock(), nil }, retry)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ctx.Err()
+	}
+
+	ok, err = m.flock.TryRLockContext(ctx, retry)
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	if !ok {
+		m.mu.RUnlock()
+		return nil, ctx.Err()
+	}
+	return m.unlockOnce(m.mu.RUnlock), nil
+}
+
+// unlockOnce returns a closure that releases the file lock and then
+// inProcUnlock (m.mu.Unlock or m.mu.RUnlock) the first time it is
+// called, and does nothing on subsequent calls. sync.Once makes the
+// closure itself safe to call from multiple goroutines, though ordinary
+// use only ever calls it from the goroutine that acquired the lock.
+func (m *Mutex) unlockOnce(inProcUnlock func()) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.flock.Unlock()
+			inProcUnlock()
+		})
+	}
+}
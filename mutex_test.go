@@ -0,0 +1,115 @@
+package gflock_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tekintian/gflock"
+
+	. "gopkg.in/check.v1"
+)
+
+type MutexSuite struct {
+	path string
+}
+
+var _ = Suite(&MutexSuite{})
+
+func (s *MutexSuite) SetUpTest(c *C) {
+	s.path = filepath.Join(c.MkDir(), "mutex")
+}
+
+func (s *MutexSuite) TestLockUnlock(c *C) {
+	m := gflock.NewMutex(s.path)
+
+	unlock, err := m.Lock()
+	c.Assert(err, IsNil)
+	c.Assert(unlock, Not(IsNil))
+
+	// unlocking more than once is a no-op, not an error.
+	unlock()
+	unlock()
+}
+
+func (s *MutexSuite) TestRLockUnlock(c *C) {
+	m := gflock.NewMutex(s.path)
+
+	unlock, err := m.RLock()
+	c.Assert(err, IsNil)
+	c.Assert(unlock, Not(IsNil))
+
+	unlock()
+}
+
+// TestLockSerializesGoroutines exercises a single *Mutex from many
+// goroutines at once. Before the concurrency fix, GFlock.Lock() short-
+// circuited to success for a goroutine that found the Mutex already
+// locked, so two goroutines could run the "critical section" below at
+// the same time and the counter would observe a value other than 1.
+func (s *MutexSuite) TestLockSerializesGoroutines(c *C) {
+	m := gflock.NewMutex(s.path)
+
+	const goroutines = 20
+	var wg sync.WaitGroup
+	var current, maxSeen int32
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := m.Lock()
+			c.Assert(err, IsNil)
+			defer unlock()
+
+			current++
+			if current > maxSeen {
+				maxSeen = current
+			}
+			current--
+		}()
+	}
+	wg.Wait()
+
+	c.Check(maxSeen, Equals, int32(1))
+}
+
+func (s *MutexSuite) TestLockContext(c *C) {
+	m := gflock.NewMutex(s.path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	unlock, err := m.LockContext(ctx, 10*time.Millisecond)
+	c.Assert(err, IsNil)
+	c.Assert(unlock, Not(IsNil))
+	unlock()
+}
+
+func (s *MutexSuite) TestLockContextTimesOutAgainstSameMutex(c *C) {
+	m := gflock.NewMutex(s.path)
+
+	unlock, err := m.Lock()
+	c.Assert(err, IsNil)
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = m.LockContext(ctx, 5*time.Millisecond)
+	c.Assert(err, Equals, context.DeadlineExceeded)
+}
+
+func (s *MutexSuite) TestRLockContext(c *C) {
+	m := gflock.NewMutex(s.path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	unlock, err := m.RLockContext(ctx, 10*time.Millisecond)
+	c.Assert(err, IsNil)
+	c.Assert(unlock, Not(IsNil))
+	unlock()
+}